@@ -0,0 +1,269 @@
+package jotdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// BSON element type tags: a subset of the full BSON spec sufficient for
+// the document shapes JotDB stores (doubles, UTF-8 strings, embedded
+// documents and arrays, booleans, null, and 32/64-bit integers).
+const (
+	bsonTypeDouble   byte = 0x01
+	bsonTypeString   byte = 0x02
+	bsonTypeDocument byte = 0x03
+	bsonTypeArray    byte = 0x04
+	bsonTypeBool     byte = 0x08
+	bsonTypeNull     byte = 0x0A
+	bsonTypeInt32    byte = 0x10
+	bsonTypeInt64    byte = 0x12
+)
+
+// marshalBSON serializes a map[string]interface{} document to BSON. Unlike
+// JSON, BSON has no top-level scalar or array form, so v must be a
+// document.
+func marshalBSON(v interface{}) ([]byte, error) {
+	doc, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("BSON codec requires a map[string]interface{}, got %T", v)
+	}
+	return marshalBSONDocument(doc)
+}
+
+func marshalBSONDocument(doc map[string]interface{}) ([]byte, error) {
+	var body []byte
+	for k, v := range doc {
+		elem, err := marshalBSONElement(k, v)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, elem...)
+	}
+	body = append(body, 0x00)
+	return prependBSONLength(body), nil
+}
+
+func marshalBSONArray(arr []interface{}) ([]byte, error) {
+	var body []byte
+	for i, v := range arr {
+		elem, err := marshalBSONElement(strconv.Itoa(i), v)
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, elem...)
+	}
+	body = append(body, 0x00)
+	return prependBSONLength(body), nil
+}
+
+// prependBSONLength prefixes body with its own total length as a 4-byte
+// little-endian int32, as required for BSON documents and arrays alike.
+func prependBSONLength(body []byte) []byte {
+	out := make([]byte, 4, 4+len(body))
+	binary.LittleEndian.PutUint32(out, uint32(4+len(body)))
+	return append(out, body...)
+}
+
+func marshalBSONElement(name string, v interface{}) ([]byte, error) {
+	switch val := v.(type) {
+	case nil:
+		return bsonElementHeader(bsonTypeNull, name), nil
+	case bool:
+		b := byte(0)
+		if val {
+			b = 1
+		}
+		return append(bsonElementHeader(bsonTypeBool, name), b), nil
+	case string:
+		strBytes := append([]byte(val), 0x00)
+		lenBuf := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lenBuf, uint32(len(strBytes)))
+		out := bsonElementHeader(bsonTypeString, name)
+		out = append(out, lenBuf...)
+		return append(out, strBytes...), nil
+	case float64:
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(val))
+		return append(bsonElementHeader(bsonTypeDouble, name), buf...), nil
+	case int:
+		return marshalBSONInt(name, int64(val)), nil
+	case int32:
+		return marshalBSONInt(name, int64(val)), nil
+	case int64:
+		return marshalBSONInt(name, val), nil
+	case map[string]interface{}:
+		docBytes, err := marshalBSONDocument(val)
+		if err != nil {
+			return nil, err
+		}
+		return append(bsonElementHeader(bsonTypeDocument, name), docBytes...), nil
+	case []interface{}:
+		arrBytes, err := marshalBSONArray(val)
+		if err != nil {
+			return nil, err
+		}
+		return append(bsonElementHeader(bsonTypeArray, name), arrBytes...), nil
+	default:
+		return nil, fmt.Errorf("BSON codec: unsupported type %T", v)
+	}
+}
+
+// marshalBSONInt always encodes as a 64-bit BSON integer, so Go int and
+// int32 values round-trip through storage as int64, matching what the
+// document codec reads them back as.
+func marshalBSONInt(name string, v int64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(v))
+	return append(bsonElementHeader(bsonTypeInt64, name), buf...)
+}
+
+func bsonElementHeader(tag byte, name string) []byte {
+	header := make([]byte, 0, 1+len(name)+1)
+	header = append(header, tag)
+	header = append(header, name...)
+	return append(header, 0x00)
+}
+
+// unmarshalBSON deserializes BSON document data into target.
+func unmarshalBSON(data []byte, target interface{}) error {
+	doc, _, err := parseBSONDocument(data, 0)
+	if err != nil {
+		return err
+	}
+	switch t := target.(type) {
+	case *interface{}:
+		*t = doc
+	case *map[string]interface{}:
+		*t = doc
+	default:
+		return decodeIntoStruct(doc, target)
+	}
+	return nil
+}
+
+func parseBSONDocument(data []byte, offset int) (map[string]interface{}, int, error) {
+	if offset+4 > len(data) {
+		return nil, 0, errors.New("truncated BSON document")
+	}
+	length := int(binary.LittleEndian.Uint32(data[offset:]))
+	if length < 5 || offset+length > len(data) {
+		return nil, 0, errors.New("truncated BSON document")
+	}
+	end := offset + length
+
+	doc := make(map[string]interface{})
+	pos := offset + 4
+	for pos < end-1 {
+		name, value, next, err := parseBSONElement(data, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		doc[name] = value
+		pos = next
+	}
+	if pos != end-1 || data[pos] != 0x00 {
+		return nil, 0, errors.New("malformed BSON document terminator")
+	}
+	return doc, end, nil
+}
+
+func parseBSONArray(data []byte, offset int) ([]interface{}, int, error) {
+	if offset+4 > len(data) {
+		return nil, 0, errors.New("truncated BSON array")
+	}
+	length := int(binary.LittleEndian.Uint32(data[offset:]))
+	if length < 5 || offset+length > len(data) {
+		return nil, 0, errors.New("truncated BSON array")
+	}
+	end := offset + length
+
+	arr := []interface{}{}
+	pos := offset + 4
+	for pos < end-1 {
+		_, value, next, err := parseBSONElement(data, pos)
+		if err != nil {
+			return nil, 0, err
+		}
+		arr = append(arr, value)
+		pos = next
+	}
+	if pos != end-1 || data[pos] != 0x00 {
+		return nil, 0, errors.New("malformed BSON array terminator")
+	}
+	return arr, end, nil
+}
+
+// parseBSONElement reads one "<tag><cstring name><value>" element starting
+// at pos, returning the element's name, decoded value, and the offset of
+// the next element.
+func parseBSONElement(data []byte, pos int) (string, interface{}, int, error) {
+	if pos >= len(data) {
+		return "", nil, 0, errors.New("truncated BSON element")
+	}
+	tag := data[pos]
+	pos++
+
+	nameStart := pos
+	for pos < len(data) && data[pos] != 0x00 {
+		pos++
+	}
+	if pos >= len(data) {
+		return "", nil, 0, errors.New("unterminated BSON element name")
+	}
+	name := string(data[nameStart:pos])
+	pos++ // skip the name's null terminator
+
+	value, next, err := parseBSONValue(data, pos, tag)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	return name, value, next, nil
+}
+
+func parseBSONValue(data []byte, pos int, tag byte) (interface{}, int, error) {
+	switch tag {
+	case bsonTypeDouble:
+		if pos+8 > len(data) {
+			return nil, 0, errors.New("truncated BSON double")
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data[pos:])), pos + 8, nil
+	case bsonTypeString:
+		if pos+4 > len(data) {
+			return nil, 0, errors.New("truncated BSON string")
+		}
+		strLen := int(binary.LittleEndian.Uint32(data[pos:]))
+		pos += 4
+		if strLen < 1 || pos+strLen > len(data) {
+			return nil, 0, errors.New("truncated BSON string")
+		}
+		return string(data[pos : pos+strLen-1]), pos + strLen, nil
+	case bsonTypeDocument:
+		doc, next, err := parseBSONDocument(data, pos)
+		return doc, next, err
+	case bsonTypeArray:
+		arr, next, err := parseBSONArray(data, pos)
+		return arr, next, err
+	case bsonTypeBool:
+		if pos+1 > len(data) {
+			return nil, 0, errors.New("truncated BSON bool")
+		}
+		return data[pos] != 0, pos + 1, nil
+	case bsonTypeNull:
+		return nil, pos, nil
+	case bsonTypeInt32:
+		if pos+4 > len(data) {
+			return nil, 0, errors.New("truncated BSON int32")
+		}
+		return int64(int32(binary.LittleEndian.Uint32(data[pos:]))), pos + 4, nil
+	case bsonTypeInt64:
+		if pos+8 > len(data) {
+			return nil, 0, errors.New("truncated BSON int64")
+		}
+		return int64(binary.LittleEndian.Uint64(data[pos:])), pos + 8, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported BSON type tag 0x%02x", tag)
+	}
+}
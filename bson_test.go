@@ -0,0 +1,73 @@
+package jotdb
+
+import "testing"
+
+func TestBSONRoundTripsMap(t *testing.T) {
+	doc := map[string]interface{}{
+		"name":   "Ada",
+		"age":    int64(36),
+		"active": true,
+		"tags":   []interface{}{"a", "b"},
+		"nested": map[string]interface{}{"x": int64(1)},
+	}
+	data, err := marshalBSON(doc)
+	if err != nil {
+		t.Fatalf("marshalBSON: %v", err)
+	}
+	var out map[string]interface{}
+	if err := unmarshalBSON(data, &out); err != nil {
+		t.Fatalf("unmarshalBSON: %v", err)
+	}
+	if out["name"] != "Ada" || out["age"] != int64(36) || out["active"] != true {
+		t.Errorf("unmarshalBSON = %v, want to match %v", out, doc)
+	}
+}
+
+func TestStoreStructWithBSONCodecHandlesNestedStruct(t *testing.T) {
+	j, err := NewJotDB(t.TempDir()+"/db", []interface{}{"addr.city"}, WithCodec(BSONCodec))
+	if err != nil {
+		t.Fatalf("NewJotDB: %v", err)
+	}
+	defer j.Close()
+
+	in := user{Name: "Ada", Addr: address{City: "NYC"}}
+	if err := j.StoreStruct("u1", in); err != nil {
+		t.Fatalf("StoreStruct with BSON codec: %v", err)
+	}
+
+	var out user
+	if err := j.Retrieve("u1", &out); err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if out != in {
+		t.Errorf("Retrieve = %+v, want %+v", out, in)
+	}
+
+	results, err := j.Query("addr.city", "NYC")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query(\"addr.city\", \"NYC\") = %v, want 1 result", results)
+	}
+}
+
+func TestDecodeDocAutoDetectsCodecByTag(t *testing.T) {
+	j, err := NewJotDB(t.TempDir()+"/db", nil, WithCodec(BSONCodec))
+	if err != nil {
+		t.Fatalf("NewJotDB: %v", err)
+	}
+	defer j.Close()
+
+	if err := j.Store("k1", map[string]interface{}{"a": int64(1)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := j.Retrieve("k1", &doc); err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if doc["a"] != int64(1) {
+		t.Errorf("Retrieve = %v, want a=1", doc)
+	}
+}
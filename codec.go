@@ -0,0 +1,105 @@
+package jotdb
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DocumentCodec (de)serializes documents to and from the bytes JotDB stores
+// on disk. Every codec's output is prefixed with its one-byte Tag, so a
+// stored document's format can be auto-detected when it is read back,
+// independent of which codec the reading JotDB instance is configured with.
+type DocumentCodec interface {
+	// Tag returns the one-byte marker this codec prefixes onto every
+	// document value it writes.
+	Tag() byte
+	// Marshal serializes v, which must be a map[string]interface{}.
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal deserializes data, previously produced by Marshal, into
+	// target.
+	Unmarshal(data []byte, target interface{}) error
+}
+
+// jsonCodec is the default DocumentCodec. It stores documents as JSON text
+// using JotDB's own hand-rolled encoder/decoder.
+type jsonCodec struct{}
+
+func (jsonCodec) Tag() byte { return 'j' }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return marshalJSON(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, target interface{}) error {
+	return unmarshalJSON(data, target)
+}
+
+// bsonCodec stores documents as BSON, so that values such as integers
+// round-trip through storage as int64 instead of needing to be re-inferred
+// from JSON text.
+type bsonCodec struct{}
+
+func (bsonCodec) Tag() byte { return 'b' }
+
+func (bsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return marshalBSON(v)
+}
+
+func (bsonCodec) Unmarshal(data []byte, target interface{}) error {
+	return unmarshalBSON(data, target)
+}
+
+// JSONCodec is the default DocumentCodec, storing documents as JSON text.
+var JSONCodec DocumentCodec = jsonCodec{}
+
+// BSONCodec stores documents as BSON instead of JSON, so values such as
+// integers round-trip through storage as int64. Pass it to WithCodec.
+var BSONCodec DocumentCodec = bsonCodec{}
+
+// codecsByTag maps every known codec's Tag byte back to the codec itself,
+// so a stored document can always be decoded regardless of which codec the
+// reading JotDB instance is configured to write with.
+var codecsByTag = map[byte]DocumentCodec{
+	jsonCodec{}.Tag(): jsonCodec{},
+	bsonCodec{}.Tag(): bsonCodec{},
+}
+
+// Option configures a JotDB instance created by NewJotDB.
+type Option func(*JotDB)
+
+// WithCodec selects the DocumentCodec JotDB uses to serialize new
+// documents. The default is the JSON codec. Documents already on disk are
+// always read back with whichever codec wrote them, so switching codecs on
+// an existing database is safe.
+func WithCodec(codec DocumentCodec) Option {
+	return func(j *JotDB) {
+		j.codec = codec
+	}
+}
+
+// encodeDoc serializes document with j's configured codec and prefixes the
+// codec's tag byte, so the stored value is self-describing.
+func (j *JotDB) encodeDoc(document interface{}) ([]byte, error) {
+	payload, err := j.codec.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	tagged := make([]byte, 0, len(payload)+1)
+	tagged = append(tagged, j.codec.Tag())
+	tagged = append(tagged, payload...)
+	return tagged, nil
+}
+
+// decodeDoc reads a stored document value - a one-byte codec tag followed
+// by that codec's encoding of the document - and unmarshals it into target
+// using whichever codec wrote it.
+func decodeDoc(data []byte, target interface{}) error {
+	if len(data) == 0 {
+		return errors.New("empty document value")
+	}
+	codec, ok := codecsByTag[data[0]]
+	if !ok {
+		return fmt.Errorf("unknown document codec tag %q", data[0])
+	}
+	return codec.Unmarshal(data[1:], target)
+}
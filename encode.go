@@ -0,0 +1,117 @@
+package jotdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// indexKeyVersion identifies the on-disk encoding used for secondary index
+// keys. Version 1 built index keys with fmt.Sprintf("%v", value), which
+// does not preserve the ordering of the underlying value. Version 2 encodes
+// values so that bitcask's byte-wise key ordering matches value ordering,
+// which RangeScan depends on, but encoded int and float values differently
+// (e.g. int64(5) and float64(5) produced different bytes). Version 3 encodes every numeric type through encodeSortableFloat,
+// since the JSON codec's decoder picks int64 or float64 for a given number
+// based only on its text (5 vs 5.0), so a value's Go type was never stable
+// enough to key an index entry by.
+const indexKeyVersion = 3
+
+// indexVersionKey is the reserved key under which the index encoding
+// version in use on disk is stored.
+const indexVersionKey = "meta:index_version"
+
+// encodeIndexValue converts value into a byte encoding that sorts in the
+// same order as value itself, so ranges of values can be scanned directly
+// as ranges of bitcask keys.
+//
+// Every numeric Go type is encoded through encodeSortableFloat rather than
+// keeping int and float encodings separate. JSON itself has only one number
+// type, and the JSON codec's decoder picks int64 or float64 for a given
+// field based on nothing more than whether its text contains a decimal
+// point or exponent - so the same stored value can come back as either type
+// depending on how it happened to be written. Two documents with the same
+// indexed value but different originating Go types (e.g. a caller-supplied
+// float64(5) versus a float64(5) read back from disk as int64(5)) must
+// produce the same index key, or Put's removal of a document's old index
+// entry and Query's lookup of a value will silently miss each other.
+func encodeIndexValue(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case string:
+		return encodeSortableString(v), nil
+	case bool:
+		if v {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case int:
+		return encodeSortableFloat(float64(v)), nil
+	case int32:
+		return encodeSortableFloat(float64(v)), nil
+	case int64:
+		return encodeSortableFloat(float64(v)), nil
+	case float32:
+		return encodeSortableFloat(float64(v)), nil
+	case float64:
+		return encodeSortableFloat(v), nil
+	case int8, int16, uint, uint8, uint16, uint32, uint64:
+		n, _ := numericValue(v)
+		return encodeSortableFloat(n), nil
+	default:
+		return nil, fmt.Errorf("value of type %T is not indexable", value)
+	}
+}
+
+// encodeSortableFloat encodes v using the standard IEEE-754 bit-flip trick:
+// flip the sign bit of non-negative floats, and flip every bit of negative
+// floats, so the resulting bytes sort in the same order as the floats they
+// represent.
+func encodeSortableFloat(v float64) []byte {
+	bits := math.Float64bits(v)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, bits)
+	return buf
+}
+
+// encodeSortableString encodes s as its raw UTF-8 bytes with embedded NUL
+// bytes escaped as 0x00 0xFF, terminated by an unescaped 0x00 0x00. A length
+// prefix (the other encodings are fixed-width and need none) would not preserve
+// ordering across strings of different lengths: "b" would sort before "ax"
+// even though "ax" < "b" lexicographically. Escaping-and-terminating instead
+// means shorter strings sort before longer strings that extend them, which
+// matches Go's string comparison, and the terminator still lets compound
+// index keys concatenate several encoded values unambiguously.
+func encodeSortableString(s string) []byte {
+	buf := make([]byte, 0, len(s)+2)
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0x00 {
+			buf = append(buf, 0x00, 0xFF)
+		} else {
+			buf = append(buf, s[i])
+		}
+	}
+	buf = append(buf, 0x00, 0x00)
+	return buf
+}
+
+// buildIndexKey builds the bitcask key for idx's index entry addressed by
+// values. values may supply one entry per field in idx for an exact-match
+// key, or fewer to address only a leading prefix of a compound index.
+func buildIndexKey(idx IndexSpec, values []interface{}) ([]byte, error) {
+	prefix := "index:" + idx.key() + ":"
+	key := make([]byte, 0, len(prefix)+8*len(values))
+	key = append(key, prefix...)
+	for _, v := range values {
+		encoded, err := encodeIndexValue(v)
+		if err != nil {
+			return nil, err
+		}
+		key = append(key, encoded...)
+	}
+	return key, nil
+}
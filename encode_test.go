@@ -0,0 +1,63 @@
+package jotdb
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeSortableStringPreservesOrder(t *testing.T) {
+	cases := []struct{ lo, hi string }{
+		{"a", "b"},
+		{"ax", "b"},  // regression: a length-prefix encoding sorted "b" first
+		{"b", "bx"},
+		{"", "a"},
+		{"abc", "abd"},
+	}
+	for _, c := range cases {
+		if c.lo >= c.hi {
+			t.Fatalf("bad test case: %q is not < %q", c.lo, c.hi)
+		}
+		lo := encodeSortableString(c.lo)
+		hi := encodeSortableString(c.hi)
+		if bytes.Compare(lo, hi) >= 0 {
+			t.Errorf("encodeSortableString(%q) >= encodeSortableString(%q), want <", c.lo, c.hi)
+		}
+	}
+}
+
+func TestRangeScanOrdersUnequalLengthStrings(t *testing.T) {
+	j := newTestDB(t, []interface{}{"name"})
+
+	docs := map[string]string{
+		"k1": "b",
+		"k2": "ax",
+		"k3": "c",
+	}
+	for k, name := range docs {
+		if err := j.Store(k, map[string]interface{}{"name": name}); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	it := j.RangeScan("name", "a", "c")
+	defer it.Close()
+
+	var got []string
+	for it.Valid() {
+		got = append(got, it.Doc()["name"].(string))
+		it.Next()
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("RangeScan: %v", err)
+	}
+
+	want := []string{"ax", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("RangeScan(\"a\", \"c\") = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("RangeScan(\"a\", \"c\") = %v, want %v", got, want)
+		}
+	}
+}
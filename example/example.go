@@ -1,13 +1,14 @@
 package main
 
 import (
-	jotdb "JotDB/V0.01"
 	"fmt"
+
+	jotdb "github.com/deepfield-ml/JotDB/tree/V0.02"
 )
 
 func main() {
-	// Initialize the store
-	store, err := jotdb.NewJotDB("./jotdb")
+	// Initialize the store with a nested dot-path index on data.name.
+	store, err := jotdb.NewJotDB("./jotdb", []interface{}{"data.name"})
 	if err != nil {
 		fmt.Println("Error:", err)
 		return
@@ -16,11 +17,11 @@ func main() {
 
 	// Example JSON document
 	doc := map[string]interface{}{
-		"id":   "doc001",
+		"id": "doc001",
 		"data": map[string]interface{}{
-			"name":  "Example",
-			"score": 42.5,
-			"tags":  []interface{}{"test", "demo"},
+			"name":   "Example",
+			"score":  42.5,
+			"tags":   []interface{}{"test", "demo"},
 			"active": true,
 			"meta":   nil,
 		},
@@ -40,9 +41,17 @@ func main() {
 	}
 	fmt.Printf("Retrieved document: %+v\n", retrieved)
 
+	// Query the nested index
+	matches, err := store.Query("data.name", "Example")
+	if err != nil {
+		fmt.Println("Error querying index:", err)
+		return
+	}
+	fmt.Printf("Documents with data.name=Example: %+v\n", matches)
+
 	// Delete the document
 	if err := store.Delete("doc001"); err != nil {
 		fmt.Println("Error deleting document:", err)
 		return
 	}
-}
\ No newline at end of file
+}
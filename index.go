@@ -0,0 +1,215 @@
+package jotdb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mills.io/bitcask/v2"
+)
+
+// IndexSpec names the document field(s) that make up a secondary index. A
+// single-element spec such as IndexSpec{"data.name"} is a simple index on a
+// dot-path field, which may be nested inside maps or arrays (a numeric path
+// segment indexes into an array). A multi-element spec such as
+// IndexSpec{"user.age", "user.country"} is a compound index: every
+// component's value is encoded into one sortable index key, so RangeScan
+// can filter on the full compound value or a leading prefix of it. Query
+// matches the full compound value only; it does not match on a prefix.
+type IndexSpec []string
+
+// key returns the identifier used to address this index from Query,
+// RangeScan, DropIndex, and Reindex.
+func (s IndexSpec) key() string {
+	return strings.Join(s, ",")
+}
+
+// extract evaluates every dot-path in the spec against docMap, returning
+// ok=false if any path is missing so the document is left out of the
+// index, matching how a simple index skips a document missing its field.
+func (s IndexSpec) extract(docMap map[string]interface{}) ([]interface{}, bool) {
+	values := make([]interface{}, 0, len(s))
+	for _, path := range s {
+		v, ok := evalPath(docMap, path)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	return values, true
+}
+
+// normalizeIndexSpec converts the index spec forms accepted by NewJotDB and
+// CreateIndex - a bare dot-path string, a []string of dot-paths for a
+// compound index, or an IndexSpec - into an IndexSpec.
+func normalizeIndexSpec(spec interface{}) (IndexSpec, error) {
+	switch s := spec.(type) {
+	case string:
+		if s == "" {
+			return nil, errors.New("index spec must name a field")
+		}
+		return IndexSpec{s}, nil
+	case []string:
+		if len(s) == 0 {
+			return nil, errors.New("index spec must name at least one field")
+		}
+		return IndexSpec(s), nil
+	case IndexSpec:
+		if len(s) == 0 {
+			return nil, errors.New("index spec must name at least one field")
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("invalid index spec type: %T", spec)
+	}
+}
+
+// evalPath walks a dot-separated path such as "user.address.city" through
+// nested maps and arrays, returning the value found there, or ok=false if
+// any segment is missing.
+func evalPath(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	for _, seg := range strings.Split(path, ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			val, ok := v[seg]
+			if !ok {
+				return nil, false
+			}
+			cur = val
+		case []interface{}:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			cur = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// indexFor returns the IndexSpec identified by field, matching either a
+// simple index's single dot-path or a compound index's comma-joined key.
+// Callers must hold j.mu.
+func (j *JotDB) indexFor(field string) (IndexSpec, bool) {
+	for _, idx := range j.indexes {
+		if idx.key() == field {
+			return idx, true
+		}
+	}
+	return nil, false
+}
+
+// CreateIndex adds a new secondary index, described by spec, and populates
+// it by scanning every existing document. spec is either a bare dot-path
+// string or a []string of dot-paths for a compound index, as accepted by
+// NewJotDB.
+func (j *JotDB) CreateIndex(spec interface{}) error {
+	idx, err := normalizeIndexSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	if _, ok := j.indexFor(idx.key()); ok {
+		j.mu.Unlock()
+		return fmt.Errorf("index %q already exists", idx.key())
+	}
+	j.indexes = append(j.indexes, idx)
+	j.mu.Unlock()
+
+	return j.Reindex(idx.key())
+}
+
+// DropIndex removes a secondary index, identified the same way CreateIndex
+// accepts one, and deletes all of its entries from the database.
+func (j *JotDB) DropIndex(spec interface{}) error {
+	idx, err := normalizeIndexSpec(spec)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	pos := -1
+	for i, existing := range j.indexes {
+		if existing.key() == idx.key() {
+			pos = i
+			break
+		}
+	}
+	if pos == -1 {
+		return fmt.Errorf("index %q does not exist", idx.key())
+	}
+
+	if err := j.deleteIndexEntries(idx); err != nil {
+		return err
+	}
+
+	j.indexes = append(j.indexes[:pos], j.indexes[pos+1:]...)
+	return nil
+}
+
+// Reindex rebuilds the index identified by field from scratch by scanning
+// every doc: key. Use it after CreateIndex on a database with existing
+// documents, or to repair an index that has drifted out of sync.
+func (j *JotDB) Reindex(field string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	idx, ok := j.indexFor(field)
+	if !ok {
+		return fmt.Errorf("index %q does not exist", field)
+	}
+
+	if err := j.deleteIndexEntries(idx); err != nil {
+		return err
+	}
+
+	tx := j.db.Transaction()
+	defer tx.Discard()
+
+	err := tx.Scan([]byte("doc:"), func(k bitcask.Key) error {
+		data, err := tx.Get(k)
+		if err != nil {
+			return err
+		}
+		var docMap map[string]interface{}
+		if err := decodeDoc(data, &docMap); err != nil {
+			return err
+		}
+		return j.putIndex(tx, idx, string(k[len("doc:"):]), docMap)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteIndexEntries removes every index: entry currently stored for idx.
+func (j *JotDB) deleteIndexEntries(idx IndexSpec) error {
+	tx := j.db.Transaction()
+	defer tx.Discard()
+
+	prefix := []byte("index:" + idx.key() + ":")
+	var staleKeys [][]byte
+	err := tx.Scan(prefix, func(k bitcask.Key) error {
+		cp := make([]byte, len(k))
+		copy(cp, k)
+		staleKeys = append(staleKeys, cp)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range staleKeys {
+		if err := tx.Delete(k); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
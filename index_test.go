@@ -0,0 +1,135 @@
+package jotdb
+
+import "testing"
+
+func TestQueryNestedDotPathIndex(t *testing.T) {
+	j := newTestDB(t, []interface{}{"data.name"})
+
+	if err := j.Store("doc1", map[string]interface{}{
+		"data": map[string]interface{}{"name": "Example"},
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	results, err := j.Query("data.name", "Example")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query(\"data.name\", \"Example\") = %v, want 1 result", results)
+	}
+}
+
+func TestQueryCompoundIndex(t *testing.T) {
+	j := newTestDB(t, []interface{}{[]string{"user.age", "user.country"}})
+
+	if err := j.Store("doc1", map[string]interface{}{
+		"user": map[string]interface{}{"age": int64(30), "country": "US"},
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Store("doc2", map[string]interface{}{
+		"user": map[string]interface{}{"age": int64(30), "country": "UK"},
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	results, err := j.Query("user.age,user.country", []interface{}{int64(30), "US"})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query(compound) = %v, want 1 result", results)
+	}
+}
+
+func TestStoreUpdatesIndexEntryOnChangedValue(t *testing.T) {
+	j := newTestDB(t, []interface{}{"group"})
+
+	if err := j.Store("k1", map[string]interface{}{"group": "X"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Store("k1", map[string]interface{}{"group": "Y"}); err != nil {
+		t.Fatalf("re-Store: %v", err)
+	}
+
+	resultsX, err := j.Query("group", "X")
+	if err != nil {
+		t.Fatalf("Query(X): %v", err)
+	}
+	if len(resultsX) != 0 {
+		t.Errorf("Query(\"group\", \"X\") after moving to Y = %v, want no results", resultsX)
+	}
+
+	resultsY, err := j.Query("group", "Y")
+	if err != nil {
+		t.Fatalf("Query(Y): %v", err)
+	}
+	if len(resultsY) != 1 {
+		t.Errorf("Query(\"group\", \"Y\") = %v, want 1 result", resultsY)
+	}
+}
+
+func TestStoreUpdatesIndexEntryOnWholeNumberFloat(t *testing.T) {
+	j := newTestDB(t, []interface{}{"score"})
+
+	if err := j.Store("k1", map[string]interface{}{"score": float64(5)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Store("k1", map[string]interface{}{"score": float64(9)}); err != nil {
+		t.Fatalf("re-Store: %v", err)
+	}
+
+	results, err := j.Query("score", float64(5))
+	if err != nil {
+		t.Fatalf("Query(5): %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Query(\"score\", 5) after moving to 9 = %v, want no results", results)
+	}
+}
+
+func TestQueryMatchesWholeNumberFloatImmediatelyAfterStore(t *testing.T) {
+	j := newTestDB(t, []interface{}{"score"})
+
+	if err := j.Store("k1", map[string]interface{}{"score": float64(5)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	results, err := j.Query("score", float64(5))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Query(\"score\", float64(5)) = %v, want 1 result", results)
+	}
+}
+
+func TestCreateIndexReindexesExistingDocs(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	if err := j.Store("doc1", map[string]interface{}{
+		"data": map[string]interface{}{"name": "Example"},
+	}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	if err := j.CreateIndex("data.name"); err != nil {
+		t.Fatalf("CreateIndex: %v", err)
+	}
+
+	results, err := j.Query("data.name", "Example")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query after CreateIndex = %v, want 1 result", results)
+	}
+
+	if err := j.DropIndex("data.name"); err != nil {
+		t.Fatalf("DropIndex: %v", err)
+	}
+	if _, err := j.Query("data.name", "Example"); err == nil {
+		t.Fatal("Query after DropIndex: want error, got nil")
+	}
+}
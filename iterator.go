@@ -0,0 +1,224 @@
+package jotdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.mills.io/bitcask/v2"
+)
+
+// DocIterator iterates over documents as produced by Scan and All (in key
+// order) and RangeScan (in indexed-value order). Callers drive it
+// LevelDB-style:
+//
+//	it := j.All()
+//	defer it.Close()
+//	for it.Valid() {
+//		key, doc := it.Key(), it.Doc()
+//		// ...
+//		it.Next()
+//	}
+//	if err := it.Err(); err != nil {
+//		// ...
+//	}
+type DocIterator interface {
+	// Valid reports whether the iterator is positioned at a document.
+	Valid() bool
+	// Next advances the iterator to the next document.
+	Next()
+	// Key returns the document key at the iterator's current position.
+	Key() string
+	// Doc returns the document at the iterator's current position.
+	Doc() map[string]interface{}
+	// Err returns the first error encountered while iterating, if any.
+	Err() error
+	// Close releases resources held by the iterator. Callers must call
+	// Close when done, even after Valid reports false.
+	Close() error
+}
+
+// docIterator is a DocIterator over a precomputed, already ordered list of
+// document keys. Documents are unmarshalled lazily, one at a time, as
+// the iterator advances.
+type docIterator struct {
+	j      *JotDB
+	keys   []string
+	pos    int
+	cur    map[string]interface{}
+	curErr error
+	err    error
+	closed bool
+}
+
+func (it *docIterator) Valid() bool {
+	return !it.closed && it.err == nil && it.pos < len(it.keys)
+}
+
+func (it *docIterator) Next() {
+	if !it.Valid() {
+		return
+	}
+	it.pos++
+	it.cur = nil
+	it.curErr = nil
+}
+
+func (it *docIterator) Key() string {
+	if !it.Valid() {
+		return ""
+	}
+	return it.keys[it.pos]
+}
+
+func (it *docIterator) Doc() map[string]interface{} {
+	if !it.Valid() {
+		return nil
+	}
+	if it.cur != nil || it.curErr != nil {
+		return it.cur
+	}
+
+	it.j.mu.RLock()
+	data, err := it.j.db.Get([]byte("doc:" + it.keys[it.pos]))
+	it.j.mu.RUnlock()
+	if err != nil {
+		it.curErr = err
+		it.err = err
+		return nil
+	}
+
+	var doc map[string]interface{}
+	if err := decodeDoc(data, &doc); err != nil {
+		it.curErr = err
+		it.err = err
+		return nil
+	}
+	it.cur = doc
+	return doc
+}
+
+func (it *docIterator) Err() error {
+	return it.err
+}
+
+func (it *docIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// Scan returns a DocIterator over every document whose key has the given
+// prefix, in key order.
+func (j *JotDB) Scan(prefix string) DocIterator {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var keys []string
+	err := j.db.Scan([]byte("doc:"+prefix), func(k bitcask.Key) error {
+		keys = append(keys, string(k[len("doc:"):]))
+		return nil
+	})
+	if err != nil {
+		return &docIterator{j: j, err: err}
+	}
+	return &docIterator{j: j, keys: keys}
+}
+
+// All returns a DocIterator over every document in the database, in key
+// order.
+func (j *JotDB) All() DocIterator {
+	return j.Scan("")
+}
+
+// RangeScan returns a DocIterator over every document whose value for field
+// falls between lo and hi inclusive, ordered by that value. field must have
+// been declared as an indexed field, and lo/hi must be of the same
+// indexable type (string, bool, or a numeric type).
+//
+// For a compound index, field is the comma-joined key returned by
+// IndexSpec.key, and lo/hi may each be a []interface{} addressing only a
+// leading prefix of the index's fields.
+func (j *JotDB) RangeScan(field string, lo, hi interface{}) DocIterator {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	idx, ok := j.indexFor(field)
+	if !ok {
+		return &docIterator{j: j, err: errors.New("field is not indexed")}
+	}
+
+	loValues, ok := lo.([]interface{})
+	if !ok {
+		loValues = []interface{}{lo}
+	}
+	hiValues, ok := hi.([]interface{})
+	if !ok {
+		hiValues = []interface{}{hi}
+	}
+	if len(loValues) > len(idx) || len(hiValues) > len(idx) {
+		return &docIterator{j: j, err: fmt.Errorf("range bounds for index %q must address at most %d field(s)", field, len(idx))}
+	}
+
+	loKey, err := buildIndexKey(idx, loValues)
+	if err != nil {
+		return &docIterator{j: j, err: err}
+	}
+	hiKey, err := buildIndexKey(idx, hiValues)
+	if err != nil {
+		return &docIterator{j: j, err: err}
+	}
+	if len(hiValues) < len(idx) {
+		// Entries whose leading fields equal a prefix hi sort after hiKey
+		// itself; raise the bound to the prefix's successor to keep them.
+		for i := len(hiKey) - 1; i >= 0; i-- {
+			if hiKey[i] != 0xFF {
+				hiKey[i]++
+				hiKey = hiKey[:i+1]
+				break
+			}
+		}
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	err = j.db.Range(loKey, hiKey, func(k bitcask.Key) error {
+		data, err := j.db.Get(k)
+		if err != nil {
+			return err
+		}
+		var keyList []string
+		if err := json.Unmarshal(data, &keyList); err != nil {
+			return err
+		}
+		for _, dk := range keyList {
+			if !seen[dk] {
+				seen[dk] = true
+				keys = append(keys, dk)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &docIterator{j: j, err: err}
+	}
+	return &docIterator{j: j, keys: keys}
+}
+
+// Page collects up to limit documents from it, after skipping the first
+// offset, and closes it when done. It is a convenience wrapper for paging
+// through large Scan/RangeScan/All results. A limit of 0 collects every
+// remaining document after offset.
+func Page(it DocIterator, offset, limit int) ([]map[string]interface{}, error) {
+	defer it.Close()
+
+	for i := 0; i < offset && it.Valid(); i++ {
+		it.Next()
+	}
+
+	var results []map[string]interface{}
+	for it.Valid() && (limit <= 0 || len(results) < limit) {
+		results = append(results, it.Doc())
+		it.Next()
+	}
+	return results, it.Err()
+}
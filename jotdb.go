@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 
 	"go.mills.io/bitcask/v2"
@@ -11,210 +12,358 @@ import (
 
 // JotDB manages local storage and retrieval of JSON documents with concurrency support and secondary indexes.
 type JotDB struct {
-	db            *bitcask.Bitcask
-	mu            sync.RWMutex
-	path          string
-	indexedFields []string
+	db      *bitcask.Bitcask
+	mu      sync.RWMutex
+	path    string
+	indexes []IndexSpec
+	codec   DocumentCodec
+	views   map[string]ViewSpec
 }
 
-// NewJotDB initializes a new JotDB instance at the given local path with specified indexed fields.
-func NewJotDB(dbPath string, indexedFields []string) (*JotDB, error) {
+// NewJotDB initializes a new JotDB instance at the given local path with the
+// given secondary indexes. Each entry in indexes is either a bare dot-path
+// string such as "data.name" for a simple index, or a []string such as
+// []string{"user.age", "user.country"} for a compound index over several
+// fields; see IndexSpec. By default documents are stored as JSON; pass
+// WithCodec to use a different DocumentCodec.
+//
+// If dbPath holds a database written before index keys carried a version
+// marker, or one whose marker names a different index key version,
+// NewJotDB returns a usable *JotDB alongside ErrIndexMigrationRequired;
+// call MigrateIndexes on it before using Query or RangeScan.
+func NewJotDB(dbPath string, indexes []interface{}, opts ...Option) (*JotDB, error) {
 	db, err := bitcask.Open(dbPath)
 	if err != nil {
 		return nil, err
 	}
-	return &JotDB{
-		db:            db,
-		path:          dbPath,
-		indexedFields: indexedFields,
-	}, nil
-}
 
-// Store stores a JSON document with the given key and updates secondary indexes.
-func (j *JotDB) Store(key string, document interface{}) error {
-	j.mu.Lock()
-	defer j.mu.Unlock()
+	specs := make([]IndexSpec, 0, len(indexes))
+	for _, spec := range indexes {
+		idx, err := normalizeIndexSpec(spec)
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+		specs = append(specs, idx)
+	}
 
-	tx := j.db.Transaction()
-	defer tx.Discard()
+	j := &JotDB{
+		db:      db,
+		path:    dbPath,
+		indexes: specs,
+		codec:   jsonCodec{},
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	if err := j.checkIndexVersion(); err != nil {
+		if errors.Is(err, ErrIndexMigrationRequired) {
+			// Unlike other NewJotDB errors, leave j open and usable: the
+			// caller needs a live handle to call MigrateIndexes on.
+			return j, err
+		}
+		db.Close()
+		return nil, err
+	}
+	return j, nil
+}
 
-	docMap, ok := document.(map[string]interface{})
-	if !ok {
-		return errors.New("document must be map[string]interface{}")
+// ErrIndexMigrationRequired is returned by NewJotDB when dbPath holds
+// index: entries but no meta:index_version marker - the signature of a
+// database last written by a version of JotDB that predates the
+// sort-order-preserving index key encoding, since that encoding is what
+// introduced the marker - and, wrapped with the version found, when the
+// marker names a version other than indexKeyVersion. Call MigrateIndexes
+// on the returned *JotDB to rebuild its indexes before using Query or
+// RangeScan.
+var ErrIndexMigrationRequired = errors.New("database index keys use a different encoding; call MigrateIndexes to upgrade")
+
+// checkIndexVersion records the index key encoding version for a fresh
+// database, or returns an error if the database on disk was last written by
+// an older version of JotDB whose index keys were not built with the
+// current sort-order-preserving encoding. Call MigrateIndexes to upgrade
+// such a database.
+func (j *JotDB) checkIndexVersion() error {
+	data, err := j.db.Get([]byte(indexVersionKey))
+	if err == nil {
+		version, err := strconv.Atoi(string(data))
+		if err != nil {
+			return fmt.Errorf("unreadable index version marker: %w", err)
+		}
+		if version != indexKeyVersion {
+			return fmt.Errorf("database has index key version %d, need %d: %w", version, indexKeyVersion, ErrIndexMigrationRequired)
+		}
+		return nil
+	}
+	if err != bitcask.ErrKeyNotFound {
+		return err
 	}
 
-	data, err := marshalJSON(document)
+	// No version marker. A brand-new database has no index: keys either;
+	// one written before the marker existed does, since indexes predate
+	// it. Treat the latter as needing migration, rather than silently
+	// trusting keys that may have been built with the old, non-sortable
+	// fmt.Sprintf("%v", value) encoding.
+	hasOldIndexes, err := j.hasAnyKeyWithPrefix("index:")
 	if err != nil {
 		return err
 	}
+	if hasOldIndexes {
+		return ErrIndexMigrationRequired
+	}
+	return j.db.Put([]byte(indexVersionKey), []byte(strconv.Itoa(indexKeyVersion)))
+}
 
-	docKey := "doc:" + key
-	err = tx.Put([]byte(docKey), data)
+// hasAnyKeyWithPrefix reports whether the database has at least one key
+// starting with prefix, stopping at the first match rather than scanning
+// every key.
+func (j *JotDB) hasAnyKeyWithPrefix(prefix string) (bool, error) {
+	errStop := errors.New("stop")
+	err := j.db.Scan([]byte(prefix), func(bitcask.Key) error {
+		return errStop
+	})
+	if err == nil {
+		return false, nil
+	}
+	if err == errStop {
+		return true, nil
+	}
+	return false, err
+}
+
+// MigrateIndexes rebuilds every secondary index using the current index key
+// encoding and updates the stored version marker. Call this once, on the
+// *JotDB returned alongside ErrIndexMigrationRequired, against a database
+// created by an older version of JotDB before using RangeScan.
+func (j *JotDB) MigrateIndexes() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	tx := j.db.Transaction()
+	defer tx.Discard()
+
+	var staleKeys [][]byte
+	err := tx.Scan([]byte("index:"), func(k bitcask.Key) error {
+		cp := make([]byte, len(k))
+		copy(cp, k)
+		staleKeys = append(staleKeys, cp)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-
-	for _, field := range j.indexedFields {
-		value, ok := docMap[field]
-		if !ok {
-			continue
-		}
-		valueStr := fmt.Sprintf("%v", value)
-		indexKey := "index:" + field + ":" + valueStr
-		current, err := tx.Get([]byte(indexKey))
-		if err != nil && err != bitcask.ErrKeyNotFound {
+	for _, k := range staleKeys {
+		if err := tx.Delete(k); err != nil {
 			return err
 		}
-		var keyList []string
-		if err == nil {
-			err = json.Unmarshal(current, &keyList)
-			if err != nil {
-				return err
-			}
-		}
-		found := false
-		for _, k := range keyList {
-			if k == key {
-				found = true
-				break
-			}
-		}
-		if !found {
-			keyList = append(keyList, key)
-		}
-		listData, err := json.Marshal(keyList)
+	}
+
+	err = tx.Scan([]byte("doc:"), func(k bitcask.Key) error {
+		data, err := tx.Get(k)
 		if err != nil {
 			return err
 		}
-		err = tx.Put([]byte(indexKey), listData)
-		if err != nil {
+		var docMap map[string]interface{}
+		if err := decodeDoc(data, &docMap); err != nil {
 			return err
 		}
+		return j.putIndexes(tx, string(k[len("doc:"):]), docMap)
+	})
+	if err != nil {
+		return err
 	}
 
-	err = tx.Commit()
-	if err != nil {
+	if err := tx.Put([]byte(indexVersionKey), []byte(strconv.Itoa(indexKeyVersion))); err != nil {
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-// Retrieve retrieves a JSON document by key and unmarshals it into the provided target.
-func (j *JotDB) Retrieve(key string, target interface{}) error {
-	j.mu.RLock()
-	defer j.mu.RUnlock()
+// Store stores a JSON document with the given key and updates secondary indexes.
+func (j *JotDB) Store(key string, document interface{}) error {
+	tx := j.Begin()
+	if err := tx.Put(key, document); err != nil {
+		tx.Discard()
+		return err
+	}
+	return tx.Commit()
+}
 
-	docKey := "doc:" + key
-	data, err := j.db.Get([]byte(docKey))
+// StoreStruct stores v, a struct or pointer to struct, under key and
+// updates secondary indexes. Fields are named as they would be by
+// encoding/json: by their `json:"name,omitempty"` tag, or their Go name if
+// untagged; a tag of "-" excludes a field. Secondary indexes are built
+// from these same reflected field values, so an indexed dot-path such as
+// "user.age" addresses the field named "age" (by tag or Go name) inside
+// the field named "user" exactly as it would address a
+// map[string]interface{} document. Retrieve with a matching struct
+// pointer target reads the document back the same way.
+func (j *JotDB) StoreStruct(key string, v interface{}) error {
+	docMap, err := structToMap(v)
 	if err != nil {
-		if err == bitcask.ErrKeyNotFound {
-			return errors.New("document not found")
-		}
 		return err
 	}
-	return unmarshalJSON(data, target)
+	return j.Store(key, docMap)
 }
 
-// Delete removes a JSON document by key and updates secondary indexes.
-func (j *JotDB) Delete(key string) error {
-	j.mu.Lock()
-	defer j.mu.Unlock()
-
-	tx := j.db.Transaction()
-	defer tx.Discard()
+// putIndexes adds key to the secondary index entry for every index whose
+// fields are present in docMap, within the given transaction.
+func (j *JotDB) putIndexes(tx *bitcask.Txn, key string, docMap map[string]interface{}) error {
+	for _, idx := range j.indexes {
+		if err := j.putIndex(tx, idx, key, docMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	docKey := "doc:" + key
-	data, err := tx.Get([]byte(docKey))
+// putIndex adds key to idx's index entry for docMap's values, within the
+// given transaction. It is a no-op if docMap is missing any of idx's
+// fields, or if one of their values is not an indexable type.
+func (j *JotDB) putIndex(tx *bitcask.Txn, idx IndexSpec, key string, docMap map[string]interface{}) error {
+	values, ok := idx.extract(docMap)
+	if !ok {
+		return nil
+	}
+	indexKey, err := buildIndexKey(idx, values)
 	if err != nil {
-		if err == bitcask.ErrKeyNotFound {
-			err = tx.Commit()
-			if err != nil {
-				return err
-			}
-			return nil
-		}
-		return err
+		return nil
 	}
 
-	var docMap map[string]interface{}
-	err = unmarshalJSON(data, &docMap)
+	current, err := tx.Get(indexKey)
+	if err != nil && err != bitcask.ErrKeyNotFound {
+		return err
+	}
+	var keyList []string
+	if err == nil {
+		err = json.Unmarshal(current, &keyList)
+		if err != nil {
+			return err
+		}
+	}
+	found := false
+	for _, k := range keyList {
+		if k == key {
+			found = true
+			break
+		}
+	}
+	if !found {
+		keyList = append(keyList, key)
+	}
+	listData, err := json.Marshal(keyList)
 	if err != nil {
 		return err
 	}
+	return tx.Put(indexKey, listData)
+}
 
-	for _, field := range j.indexedFields {
-		value, ok := docMap[field]
-		if !ok {
-			continue
-		}
-		valueStr := fmt.Sprintf("%v", value)
-		indexKey := "index:" + field + ":" + valueStr
-		current, err := tx.Get([]byte(indexKey))
-		if err != nil && err != bitcask.ErrKeyNotFound {
+// removeIndexes removes key from the secondary index entry for every index
+// whose fields are present in docMap, within the given transaction.
+func (j *JotDB) removeIndexes(tx *bitcask.Txn, key string, docMap map[string]interface{}) error {
+	for _, idx := range j.indexes {
+		if err := j.removeIndex(tx, idx, key, docMap); err != nil {
 			return err
 		}
-		if err == bitcask.ErrKeyNotFound {
-			continue
+	}
+	return nil
+}
+
+// removeIndex removes key from idx's index entry for docMap's values,
+// within the given transaction. It is a no-op if docMap is missing any of
+// idx's fields, or if the entry does not exist.
+func (j *JotDB) removeIndex(tx *bitcask.Txn, idx IndexSpec, key string, docMap map[string]interface{}) error {
+	values, ok := idx.extract(docMap)
+	if !ok {
+		return nil
+	}
+	indexKey, err := buildIndexKey(idx, values)
+	if err != nil {
+		return nil
+	}
+
+	current, err := tx.Get(indexKey)
+	if err != nil && err != bitcask.ErrKeyNotFound {
+		return err
+	}
+	if err == bitcask.ErrKeyNotFound {
+		return nil
+	}
+	var keyList []string
+	err = json.Unmarshal(current, &keyList)
+	if err != nil {
+		return err
+	}
+	newList := []string{}
+	for _, k := range keyList {
+		if k != key {
+			newList = append(newList, k)
 		}
-		var keyList []string
-		err = json.Unmarshal(current, &keyList)
+	}
+	if len(newList) > 0 {
+		listData, err := json.Marshal(newList)
 		if err != nil {
 			return err
 		}
-		newList := []string{}
-		for _, k := range keyList {
-			if k != key {
-				newList = append(newList, k)
-			}
-		}
-		if len(newList) > 0 {
-			listData, err := json.Marshal(newList)
-			if err != nil {
-				return err
-			}
-			err = tx.Put([]byte(indexKey), listData)
-			if err != nil {
-				return err
-			}
-		} else {
-			err = tx.Delete([]byte(indexKey))
-			if err != nil {
-				return err
-			}
-		}
+		return tx.Put(indexKey, listData)
 	}
+	return tx.Delete(indexKey)
+}
+
+// Retrieve retrieves a JSON document by key and unmarshals it into the
+// provided target, which may be a struct pointer (honoring `json` tags),
+// *map[string]interface{}, *[]interface{}, or *interface{}.
+func (j *JotDB) Retrieve(key string, target interface{}) error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 
-	err = tx.Delete([]byte(docKey))
+	docKey := "doc:" + key
+	data, err := j.db.Get([]byte(docKey))
 	if err != nil {
+		if err == bitcask.ErrKeyNotFound {
+			return errors.New("document not found")
+		}
 		return err
 	}
+	return decodeDoc(data, target)
+}
 
-	err = tx.Commit()
-	if err != nil {
+// Delete removes a JSON document by key and updates secondary indexes.
+func (j *JotDB) Delete(key string) error {
+	tx := j.Begin()
+	if err := tx.Delete(key); err != nil {
+		tx.Discard()
 		return err
 	}
-	return nil
+	return tx.Commit()
 }
 
-// Query retrieves documents by a specific indexed field and value.
+// Query retrieves documents matching value for a specific indexed field.
+// For a compound index, field is the comma-joined key returned by
+// IndexSpec.key (as used by CreateIndex/DropIndex/Reindex) and value must
+// be a []interface{} with one entry per component field, in order.
 func (j *JotDB) Query(field string, value interface{}) ([]map[string]interface{}, error) {
 	j.mu.RLock()
 	defer j.mu.RUnlock()
 
-	found := false
-	for _, f := range j.indexedFields {
-		if f == field {
-			found = true
-			break
-		}
-	}
-	if !found {
+	idx, ok := j.indexFor(field)
+	if !ok {
 		return nil, errors.New("field is not indexed")
 	}
 
-	valueStr := fmt.Sprintf("%v", value)
-	indexKey := "index:" + field + ":" + valueStr
-	data, err := j.db.Get([]byte(indexKey))
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	if len(values) != len(idx) {
+		return nil, fmt.Errorf("query for index %q needs %d value(s), got %d", field, len(idx), len(values))
+	}
+
+	indexKey, err := buildIndexKey(idx, values)
+	if err != nil {
+		return nil, err
+	}
+	data, err := j.db.Get(indexKey)
 	if err != nil {
 		if err == bitcask.ErrKeyNotFound {
 			return []map[string]interface{}{}, nil
@@ -235,7 +384,7 @@ func (j *JotDB) Query(field string, value interface{}) ([]map[string]interface{}
 			continue
 		}
 		var doc map[string]interface{}
-		err = unmarshalJSON(docData, &doc)
+		err = decodeDoc(docData, &doc)
 		if err != nil {
 			continue
 		}
@@ -249,74 +398,15 @@ func (j *JotDB) BatchStore(keys []string, documents []interface{}) error {
 	if len(keys) != len(documents) {
 		return errors.New("keys and documents must have the same length")
 	}
-	j.mu.Lock()
-	defer j.mu.Unlock()
-
-	tx := j.db.Transaction()
-	defer tx.Discard()
 
+	tx := j.Begin()
 	for i, key := range keys {
-		document := documents[i]
-		docMap, ok := document.(map[string]interface{})
-		if !ok {
-			return errors.New("document must be map[string]interface{}")
-		}
-
-		data, err := marshalJSON(document)
-		if err != nil {
-			return err
-		}
-
-		docKey := "doc:" + key
-		err = tx.Put([]byte(docKey), data)
-		if err != nil {
+		if err := tx.Put(key, documents[i]); err != nil {
+			tx.Discard()
 			return err
 		}
-
-		for _, field := range j.indexedFields {
-			value, ok := docMap[field]
-			if !ok {
-				continue
-			}
-			valueStr := fmt.Sprintf("%v", value)
-			indexKey := "index:" + field + ":" + valueStr
-			current, err := tx.Get([]byte(indexKey))
-			if err != nil && err != bitcask.ErrKeyNotFound {
-				return err
-			}
-			var keyList []string
-			if err == nil {
-				err = json.Unmarshal(current, &keyList)
-				if err != nil {
-					return err
-				}
-			}
-			found := false
-			for _, k := range keyList {
-				if k == key {
-					found = true
-					break
-				}
-			}
-			if !found {
-				keyList = append(keyList, key)
-			}
-			listData, err := json.Marshal(keyList)
-			if err != nil {
-				return err
-			}
-			err = tx.Put([]byte(indexKey), listData)
-			if err != nil {
-				return err
-			}
-		}
 	}
-
-	err := tx.Commit()
-	if err != nil {
-		return err
-	}
-	return nil
+	return tx.Commit()
 }
 
 // BatchRetrieve retrieves multiple JSON documents by their keys.
@@ -335,7 +425,7 @@ func (j *JotDB) BatchRetrieve(keys []string) ([]map[string]interface{}, error) {
 			return nil, err
 		}
 		var doc map[string]interface{}
-		err = unmarshalJSON(data, &doc)
+		err = decodeDoc(data, &doc)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,75 @@
+package jotdb
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.mills.io/bitcask/v2"
+)
+
+func TestNewJotDBDetectsPreVersioningDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+
+	// Build a database the way a pre-versioning JotDB would have left one:
+	// a document and an old-style index: entry, but no meta:index_version
+	// marker, since that marker didn't exist yet.
+	raw, err := bitcask.Open(dbPath)
+	if err != nil {
+		t.Fatalf("bitcask.Open: %v", err)
+	}
+	if err := raw.Put([]byte("doc:u1"), append([]byte{'j'}, []byte(`{"age":30}`)...)); err != nil {
+		t.Fatalf("Put doc: %v", err)
+	}
+	if err := raw.Put([]byte("index:age:30"), []byte(`["u1"]`)); err != nil {
+		t.Fatalf("Put index: %v", err)
+	}
+	if err := raw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	j, err := NewJotDB(dbPath, []interface{}{"age"})
+	if !errors.Is(err, ErrIndexMigrationRequired) {
+		t.Fatalf("NewJotDB on pre-versioning database: got err %v, want ErrIndexMigrationRequired", err)
+	}
+	if j == nil {
+		t.Fatal("NewJotDB on pre-versioning database: got nil *JotDB, want a usable handle to call MigrateIndexes on")
+	}
+	defer j.Close()
+
+	if err := j.MigrateIndexes(); err != nil {
+		t.Fatalf("MigrateIndexes: %v", err)
+	}
+
+	results, err := j.Query("age", int64(30))
+	if err != nil {
+		t.Fatalf("Query after MigrateIndexes: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query(\"age\", 30) after MigrateIndexes = %v, want 1 result", results)
+	}
+}
+
+func TestNewJotDBFreshDatabaseStampsVersion(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+
+	j, err := NewJotDB(dbPath, []interface{}{"age"})
+	if err != nil {
+		t.Fatalf("NewJotDB: %v", err)
+	}
+	if err := j.Store("u1", map[string]interface{}{"age": int64(30)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// A fresh database (now with index: keys, but also the version marker
+	// stamped on first open) must not be mistaken for one that needs
+	// migration.
+	j2, err := NewJotDB(dbPath, []interface{}{"age"})
+	if err != nil {
+		t.Fatalf("reopening a fresh database: %v", err)
+	}
+	j2.Close()
+}
@@ -2,11 +2,25 @@ package jotdb
 
 import (
 	"bytes"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io"
+	"reflect"
 	"strconv"
 	"unicode/utf8"
 )
 
+// jsonWriter is the subset of methods writeJSON needs to emit JSON text.
+// Both *bytes.Buffer, used by marshalJSON's in-memory encoding, and
+// *bufio.Writer, used by Encoder's streaming output, satisfy it.
+type jsonWriter interface {
+	io.Writer
+	WriteByte(byte) error
+	WriteRune(rune) (int, error)
+	WriteString(string) (int, error)
+}
+
 // marshalJSON serializes a Go value to JSON.
 func marshalJSON(v interface{}) ([]byte, error) {
 	var buf bytes.Buffer
@@ -16,8 +30,8 @@ func marshalJSON(v interface{}) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-// writeJSON writes a Go value as JSON to the buffer.
-func writeJSON(buf *bytes.Buffer, v interface{}) error {
+// writeJSON writes a Go value as JSON to buf.
+func writeJSON(buf jsonWriter, v interface{}) error {
 	switch val := v.(type) {
 	case nil:
 		buf.WriteString("null")
@@ -27,6 +41,28 @@ func writeJSON(buf *bytes.Buffer, v interface{}) error {
 		buf.WriteString(strconv.FormatFloat(val, 'f', -1, 64))
 	case int:
 		buf.WriteString(strconv.Itoa(val))
+	case int8:
+		buf.WriteString(strconv.FormatInt(int64(val), 10))
+	case int16:
+		buf.WriteString(strconv.FormatInt(int64(val), 10))
+	case int32:
+		buf.WriteString(strconv.FormatInt(int64(val), 10))
+	case int64:
+		buf.WriteString(strconv.FormatInt(val, 10))
+	case uint:
+		buf.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint8:
+		buf.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint16:
+		buf.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint32:
+		buf.WriteString(strconv.FormatUint(uint64(val), 10))
+	case uint64:
+		buf.WriteString(strconv.FormatUint(val, 10))
+	case json.Number:
+		buf.WriteString(val.String())
+	case []byte:
+		escapeString(buf, base64.StdEncoding.EncodeToString(val))
 	case bool:
 		if val {
 			buf.WriteString("true")
@@ -60,13 +96,24 @@ func writeJSON(buf *bytes.Buffer, v interface{}) error {
 		}
 		buf.WriteByte(']')
 	default:
-		return fmt.Errorf("unsupported type: %T", v)
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return fmt.Errorf("unsupported type: %T", v)
+		}
+		m, err := structToMap(v)
+		if err != nil {
+			return err
+		}
+		return writeJSON(buf, m)
 	}
 	return nil
 }
 
-// escapeString writes a JSON-escaped string to the buffer.
-func escapeString(buf *bytes.Buffer, s string) {
+// escapeString writes a JSON-escaped string to buf.
+func escapeString(buf jsonWriter, s string) {
 	buf.WriteByte('"')
 	for _, r := range s {
 		switch r {
@@ -0,0 +1,308 @@
+package jotdb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes successive JSON-encoded documents to an output stream,
+// one per Encode call, without ever materializing more than one document
+// in memory at a time.
+type Encoder struct {
+	w *bufio.Writer
+}
+
+// NewEncoder returns an Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+// Encode writes v to the stream as JSON followed by a newline, and flushes
+// the underlying writer.
+func (e *Encoder) Encode(v interface{}) error {
+	if err := writeJSON(e.w, v); err != nil {
+		return err
+	}
+	if err := e.w.WriteByte('\n'); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// Decoder reads successive JSON-encoded documents from an input stream.
+// Unlike unmarshalJSON, which parses a []byte already fully read into
+// memory, Decoder parses directly off the underlying reader one byte at a
+// time, so a large document is built up as it is read rather than
+// requiring its entire raw encoding to be buffered before parsing starts.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a Decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next JSON value from the stream and unmarshals it into
+// target, which may be a struct pointer, *map[string]interface{},
+// *[]interface{}, or *interface{}, exactly as unmarshalJSON accepts. It
+// returns io.EOF once the stream is exhausted.
+func (d *Decoder) Decode(target interface{}) error {
+	v, err := d.readValue()
+	if err != nil {
+		return err
+	}
+	return assignParsedJSON(v, target)
+}
+
+// readValue reads and parses exactly one JSON value from the stream,
+// recursive-descent style, consuming bytes from the underlying
+// bufio.Reader as it goes rather than buffering the value's raw bytes
+// first.
+func (d *Decoder) readValue() (interface{}, error) {
+	b, err := d.skipWhitespace()
+	if err != nil {
+		return nil, err
+	}
+	return d.readValueStartingWith(b)
+}
+
+// skipWhitespace reads and discards whitespace bytes, returning the first
+// non-whitespace byte it encounters.
+func (d *Decoder) skipWhitespace() (byte, error) {
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\n', '\r', '\t':
+			continue
+		default:
+			return b, nil
+		}
+	}
+}
+
+// readValueStartingWith parses one JSON value whose first non-whitespace
+// byte, first, has already been consumed from the stream.
+func (d *Decoder) readValueStartingWith(first byte) (interface{}, error) {
+	switch {
+	case first == '{':
+		return d.readObject()
+	case first == '[':
+		return d.readArray()
+	case first == '"':
+		return d.readStringBody()
+	case first == 't':
+		return true, d.expectLiteral("rue")
+	case first == 'f':
+		return false, d.expectLiteral("alse")
+	case first == 'n':
+		return nil, d.expectLiteral("ull")
+	case first == '-' || (first >= '0' && first <= '9'):
+		return d.readNumber(first)
+	default:
+		return nil, fmt.Errorf("invalid JSON byte %q", first)
+	}
+}
+
+// expectLiteral consumes exactly len(rest) bytes and errors if they don't
+// match rest, for the tail of a true/false/null literal whose first byte
+// the caller has already matched.
+func (d *Decoder) expectLiteral(rest string) error {
+	for i := 0; i < len(rest); i++ {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != rest[i] {
+			return fmt.Errorf("invalid literal, expected %q", rest)
+		}
+	}
+	return nil
+}
+
+// readObject parses a JSON object whose opening '{' has already been
+// consumed.
+func (d *Decoder) readObject() (map[string]interface{}, error) {
+	obj := make(map[string]interface{})
+	b, err := d.skipWhitespace()
+	if err != nil {
+		return nil, err
+	}
+	if b == '}' {
+		return obj, nil
+	}
+
+	for {
+		if b != '"' {
+			return nil, fmt.Errorf("expected string key, got %q", b)
+		}
+		key, err := d.readStringBody()
+		if err != nil {
+			return nil, err
+		}
+		b, err = d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if b != ':' {
+			return nil, fmt.Errorf("expected ':', got %q", b)
+		}
+		valStart, err := d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readValueStartingWith(valStart)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+
+		b, err = d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if b == '}' {
+			return obj, nil
+		}
+		if b != ',' {
+			return nil, fmt.Errorf("expected ',' or '}', got %q", b)
+		}
+		b, err = d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readArray parses a JSON array whose opening '[' has already been
+// consumed.
+func (d *Decoder) readArray() ([]interface{}, error) {
+	arr := []interface{}{}
+	b, err := d.skipWhitespace()
+	if err != nil {
+		return nil, err
+	}
+	if b == ']' {
+		return arr, nil
+	}
+
+	for {
+		value, err := d.readValueStartingWith(b)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+
+		b, err = d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if b == ']' {
+			return arr, nil
+		}
+		if b != ',' {
+			return nil, fmt.Errorf("expected ',' or ']', got %q", b)
+		}
+		b, err = d.skipWhitespace()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readStringBody parses a JSON string whose opening '"' has already been
+// consumed, up to and including its closing '"'.
+func (d *Decoder) readStringBody() (string, error) {
+	var sb strings.Builder
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == '"' {
+			return sb.String(), nil
+		}
+		if b != '\\' {
+			sb.WriteByte(b)
+			continue
+		}
+
+		esc, err := d.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch esc {
+		case '"', '\\', '/':
+			sb.WriteByte(esc)
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'u':
+			var hex [4]byte
+			for i := range hex {
+				hb, err := d.r.ReadByte()
+				if err != nil {
+					return "", err
+				}
+				hex[i] = hb
+			}
+			code, err := strconv.ParseUint(string(hex[:]), 16, 32)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(rune(code))
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c", esc)
+		}
+	}
+}
+
+// readNumber parses a JSON number whose first byte, first, has already
+// been consumed from the stream.
+func (d *Decoder) readNumber(first byte) (interface{}, error) {
+	buf := []byte{first}
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if b == '-' || b == '+' || b == '.' || b == 'e' || b == 'E' || (b >= '0' && b <= '9') {
+			buf = append(buf, b)
+			continue
+		}
+		if err := d.r.UnreadByte(); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	numStr := string(buf)
+	if strings.ContainsAny(numStr, ".eE") {
+		f, err := strconv.ParseFloat(numStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q: %w", numStr, err)
+		}
+		return f, nil
+	}
+	i, err := strconv.ParseInt(numStr, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integer %q: %w", numStr, err)
+	}
+	return i, nil
+}
@@ -0,0 +1,76 @@
+package jotdb
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEncoderDecoderRoundTripsMultipleDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+
+	docs := []map[string]interface{}{
+		{"a": int64(1), "b": "x"},
+		{"nested": map[string]interface{}{"c": []interface{}{int64(1), int64(2), "three"}}},
+		{"flag": true, "missing": nil},
+	}
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+	}
+
+	dec := NewDecoder(&buf)
+	for i, want := range docs {
+		var got map[string]interface{}
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("Decode document %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("document %d = %v, want %v", i, got, want)
+		}
+	}
+
+	if err := dec.Decode(new(map[string]interface{})); err != io.EOF {
+		t.Errorf("Decode past the last document: got %v, want io.EOF", err)
+	}
+}
+
+func TestDecoderDecodesDirectlyIntoStruct(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(user{Name: "Ada", Addr: address{City: "NYC"}}); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	var out user
+	if err := NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if out.Name != "Ada" || out.Addr.City != "NYC" {
+		t.Errorf("Decode = %+v, want Name=Ada Addr.City=NYC", out)
+	}
+}
+
+func TestDecoderParsesNumbersAndLiterals(t *testing.T) {
+	r := bytes.NewReader([]byte(`{"i":42,"f":3.5,"neg":-7,"t":true,"f2":false,"n":null}`))
+	var doc map[string]interface{}
+	if err := NewDecoder(r).Decode(&doc); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if doc["i"] != int64(42) || doc["f"] != float64(3.5) || doc["neg"] != int64(-7) {
+		t.Errorf("Decode numbers = %v", doc)
+	}
+	if doc["t"] != true || doc["f2"] != false || doc["n"] != nil {
+		t.Errorf("Decode literals = %v", doc)
+	}
+}
+
+func TestDecoderErrorsOnTruncatedInput(t *testing.T) {
+	r := bytes.NewReader([]byte(`{"a":`))
+	var doc map[string]interface{}
+	if err := NewDecoder(r).Decode(&doc); err == nil {
+		t.Error("Decode of truncated input: got nil error, want an error")
+	}
+}
@@ -0,0 +1,261 @@
+package jotdb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// structFieldName returns the JSON field name and omitempty setting
+// declared by f's `json:"name,omitempty"` tag. A tag of "-" excludes the
+// field entirely (skip is true). A field without a tag uses its Go name.
+func structFieldName(f reflect.StructField) (name string, omitempty, skip bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = f.Name
+	if parts[0] != "" {
+		name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+// structToMap converts a struct, or pointer to struct, to the
+// map[string]interface{} representation JotDB stores and indexes
+// documents from, using each exported field's `json` tag.
+func structToMap(v interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("cannot store nil %s", rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("document must be map[string]interface{} or struct, got %T", v)
+	}
+
+	out := make(map[string]interface{})
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name, omitempty, skip := structFieldName(field)
+		if skip {
+			continue
+		}
+		fv := rv.Field(i)
+		if omitempty && fv.IsZero() {
+			continue
+		}
+		val, err := toDocValue(fv)
+		if err != nil {
+			return nil, err
+		}
+		out[name] = val
+	}
+	return out, nil
+}
+
+// toDocValue recursively converts rv into the map[string]interface{} /
+// []interface{} representation JotDB stores and indexes documents from,
+// so a nested struct, slice of structs, or map of structs ends up exactly
+// as it would if the caller had built the document by hand with plain
+// maps. Without this, a nested struct field would be left as a raw Go
+// struct value, which putIndex/evalPath cannot walk and the BSON codec
+// cannot marshal. []byte is left untouched: writeJSON encodes it as a
+// base64 string, while marshalBSONElement rejects it as unsupported.
+func toDocValue(rv reflect.Value) (interface{}, error) {
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		return structToMap(rv.Interface())
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			v, err := toDocValue(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out[fmt.Sprint(iter.Key().Interface())] = v
+		}
+		return out, nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Interface(), nil // []byte
+		}
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			v, err := toDocValue(rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	default:
+		return rv.Interface(), nil
+	}
+}
+
+// decodeIntoStruct populates the struct pointed to by target from parsed,
+// a value produced by the JSON or BSON parser, matching fields by their
+// `json` tag (or Go name).
+func decodeIntoStruct(parsed interface{}, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported target type: %T", target)
+	}
+	rv = rv.Elem()
+
+	obj, ok := parsed.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected object to decode into %s, got %T", rv.Type(), parsed)
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name, _, skip := structFieldName(field)
+		if skip {
+			continue
+		}
+		raw, ok := obj[name]
+		if !ok {
+			continue
+		}
+		if err := assignValue(rv.Field(i), raw); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// assignValue assigns a value produced by the JSON or BSON parser (nil,
+// bool, string, int64, float64, map[string]interface{}, or
+// []interface{}) into dst, converting numeric types and recursing into
+// nested structs, slices, and maps as needed.
+func assignValue(dst reflect.Value, raw interface{}) error {
+	if raw == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		return decodeIntoStruct(raw, dst.Addr().Interface())
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assignValue(dst.Elem(), raw)
+	case reflect.Slice:
+		arr, ok := raw.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected array, got %T", raw)
+		}
+		slice := reflect.MakeSlice(dst.Type(), len(arr), len(arr))
+		for i, elem := range arr {
+			if err := assignValue(slice.Index(i), elem); err != nil {
+				return err
+			}
+		}
+		dst.Set(slice)
+		return nil
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected object, got %T", raw)
+		}
+		m := reflect.MakeMapWithSize(dst.Type(), len(obj))
+		for k, v := range obj {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := assignValue(elem, v); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(k), elem)
+		}
+		dst.Set(m)
+		return nil
+	case reflect.Interface:
+		dst.Set(reflect.ValueOf(raw))
+		return nil
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("expected string, got %T", raw)
+		}
+		dst.SetString(s)
+		return nil
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return fmt.Errorf("expected bool, got %T", raw)
+		}
+		dst.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := asInt64(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := asInt64(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(uint64(n))
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := asFloat64(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(f)
+		return nil
+	default:
+		return fmt.Errorf("unsupported field type %s", dst.Type())
+	}
+}
+
+func asInt64(raw interface{}) (int64, error) {
+	switch v := raw.(type) {
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("expected number, got %T", raw)
+	}
+}
+
+func asFloat64(raw interface{}) (float64, error) {
+	switch v := raw.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("expected number, got %T", raw)
+	}
+}
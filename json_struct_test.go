@@ -0,0 +1,45 @@
+package jotdb
+
+import "testing"
+
+type address struct {
+	City string `json:"city"`
+}
+
+type user struct {
+	Name string  `json:"name"`
+	Addr address `json:"addr"`
+}
+
+func TestStoreStructIndexesNestedField(t *testing.T) {
+	j := newTestDB(t, []interface{}{"addr.city"})
+
+	if err := j.StoreStruct("u1", user{Name: "Ada", Addr: address{City: "NYC"}}); err != nil {
+		t.Fatalf("StoreStruct: %v", err)
+	}
+
+	results, err := j.Query("addr.city", "NYC")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query(\"addr.city\", \"NYC\") = %v, want 1 result", results)
+	}
+}
+
+func TestStoreStructRetrievesIntoStruct(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	in := user{Name: "Ada", Addr: address{City: "NYC"}}
+	if err := j.StoreStruct("u1", in); err != nil {
+		t.Fatalf("StoreStruct: %v", err)
+	}
+
+	var out user
+	if err := j.Retrieve("u1", &out); err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if out != in {
+		t.Errorf("Retrieve = %+v, want %+v", out, in)
+	}
+}
@@ -15,7 +15,14 @@ func unmarshalJSON(data []byte, target interface{}) error {
 	if err != nil {
 		return err
 	}
+	return assignParsedJSON(v, target)
+}
 
+// assignParsedJSON places a value produced by either JSON parser -
+// jsonParser's in-memory parse or Decoder's incremental read - into target,
+// which may be a struct pointer, *map[string]interface{}, *[]interface{},
+// or *interface{}.
+func assignParsedJSON(v interface{}, target interface{}) error {
 	switch t := target.(type) {
 	case *interface{}:
 		*t = v
@@ -32,7 +39,7 @@ func unmarshalJSON(data []byte, target interface{}) error {
 			return fmt.Errorf("expected array, got %T", v)
 		}
 	default:
-		return fmt.Errorf("unsupported target type: %T", target)
+		return decodeIntoStruct(v, target)
 	}
 	return nil
 }
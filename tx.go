@@ -0,0 +1,183 @@
+package jotdb
+
+import (
+	"errors"
+
+	"go.mills.io/bitcask/v2"
+)
+
+// ErrTxDone is returned when a transaction method is called after the
+// transaction has already been committed or discarded.
+var ErrTxDone = errors.New("transaction already committed or discarded")
+
+// Tx is a staged set of writes against a JotDB instance. Put and Delete
+// calls made through a Tx are not visible to other callers until Commit is
+// called, and can be abandoned entirely with Discard. A Tx wraps a single
+// underlying bitcask.Txn, so every staged operation is written to the
+// database as one atomic batch at Commit time.
+//
+// A Tx holds JotDB's write lock for its entire lifetime, so callers must
+// always pair Begin with exactly one Commit or Discard call. An early
+// return or panic between Begin and that call leaves the lock held
+// forever, wedging every other method on the JotDB instance; WithTx is the
+// recommended entry point, since it guarantees the pairing for you.
+type Tx struct {
+	j    *JotDB
+	txn  *bitcask.Txn
+	done bool
+}
+
+// Begin starts a new transaction against the database. The caller must call
+// Commit or Discard on the returned Tx to release JotDB's write lock.
+func (j *JotDB) Begin() *Tx {
+	j.mu.Lock()
+	return &Tx{
+		j:   j,
+		txn: j.db.Transaction(),
+	}
+}
+
+// Put stages a document write under key, updating secondary indexes and
+// views, without committing it to the database. If key already holds a
+// document, Put first removes it from whichever index and view entries its
+// old values placed it in, so a changed indexed or GroupBy field does not
+// leave the document double-counted under its old value as well as its new
+// one.
+func (t *Tx) Put(key string, document interface{}) error {
+	if t.done {
+		return ErrTxDone
+	}
+
+	docMap, ok := document.(map[string]interface{})
+	if !ok {
+		return errors.New("document must be map[string]interface{}")
+	}
+
+	docKey := "doc:" + key
+	old, err := t.txn.Get([]byte(docKey))
+	if err != nil && err != bitcask.ErrKeyNotFound {
+		return err
+	}
+	if err == nil {
+		var oldMap map[string]interface{}
+		if err := decodeDoc(old, &oldMap); err != nil {
+			return err
+		}
+		if err := t.j.removeIndexes(t.txn, key, oldMap); err != nil {
+			return err
+		}
+		if err := t.j.removeViews(t.txn, key, oldMap); err != nil {
+			return err
+		}
+	}
+
+	data, err := t.j.encodeDoc(document)
+	if err != nil {
+		return err
+	}
+	if err := t.txn.Put([]byte(docKey), data); err != nil {
+		return err
+	}
+
+	if err := t.j.putIndexes(t.txn, key, docMap); err != nil {
+		return err
+	}
+	return t.j.putViews(t.txn, key, docMap)
+}
+
+// Delete stages removal of the document under key, updating secondary
+// indexes, without committing it to the database. Deleting a key that does
+// not exist is a no-op.
+func (t *Tx) Delete(key string) error {
+	if t.done {
+		return ErrTxDone
+	}
+
+	docKey := "doc:" + key
+	data, err := t.txn.Get([]byte(docKey))
+	if err != nil {
+		if err == bitcask.ErrKeyNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var docMap map[string]interface{}
+	if err := decodeDoc(data, &docMap); err != nil {
+		return err
+	}
+
+	if err := t.j.removeIndexes(t.txn, key, docMap); err != nil {
+		return err
+	}
+	if err := t.j.removeViews(t.txn, key, docMap); err != nil {
+		return err
+	}
+
+	return t.txn.Delete([]byte(docKey))
+}
+
+// Get reads a document as staged within the transaction, reflecting any
+// earlier Put/Delete calls made through it, and unmarshals it into target.
+func (t *Tx) Get(key string, target interface{}) error {
+	if t.done {
+		return ErrTxDone
+	}
+
+	docKey := "doc:" + key
+	data, err := t.txn.Get([]byte(docKey))
+	if err != nil {
+		if err == bitcask.ErrKeyNotFound {
+			return errors.New("document not found")
+		}
+		return err
+	}
+	return decodeDoc(data, target)
+}
+
+// Commit writes all staged operations to the database atomically and
+// releases JotDB's write lock. Commit must not be called more than once.
+func (t *Tx) Commit() error {
+	if t.done {
+		return ErrTxDone
+	}
+	t.done = true
+	defer t.j.mu.Unlock()
+	return t.txn.Commit()
+}
+
+// Discard abandons the transaction without writing anything and releases
+// JotDB's write lock. Discard is a no-op if the transaction was already
+// committed or discarded.
+func (t *Tx) Discard() {
+	if t.done {
+		return
+	}
+	t.done = true
+	t.txn.Discard()
+	t.j.mu.Unlock()
+}
+
+// WithTx runs fn against a new transaction, committing it if fn returns a
+// nil error and discarding it otherwise - including when fn panics, in
+// which case WithTx discards the transaction and re-panics. This is the
+// recommended way to use a Tx: a bare Begin paired with a later Commit or
+// Discard call left JotDB's write lock wedged forever if a caller returned
+// early or panicked in between, since nothing ran to release it.
+func (j *JotDB) WithTx(fn func(*Tx) error) (err error) {
+	tx := j.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Discard()
+			panic(r)
+		}
+		if err != nil {
+			tx.Discard()
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,147 @@
+package jotdb
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func newTestDB(t *testing.T, indexes []interface{}) *JotDB {
+	t.Helper()
+	j, err := NewJotDB(filepath.Join(t.TempDir(), "db"), indexes)
+	if err != nil {
+		t.Fatalf("NewJotDB: %v", err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return j
+}
+
+func TestTxCommitIsVisible(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	tx := j.Begin()
+	if err := tx.Put("k1", map[string]interface{}{"a": float64(1)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := j.Retrieve("k1", &doc); err != nil {
+		t.Fatalf("Retrieve after Commit: %v", err)
+	}
+	if doc["a"] != int64(1) {
+		t.Errorf("got doc %v, want a=1", doc)
+	}
+}
+
+func TestTxDiscardIsNotVisible(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	tx := j.Begin()
+	if err := tx.Put("k1", map[string]interface{}{"a": float64(1)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	tx.Discard()
+
+	var doc map[string]interface{}
+	if err := j.Retrieve("k1", &doc); err == nil {
+		t.Errorf("Retrieve after Discard: got %v, want not-found error", doc)
+	}
+}
+
+func TestTxGetSeesStagedWrites(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	tx := j.Begin()
+	defer tx.Discard()
+
+	if err := tx.Put("k1", map[string]interface{}{"a": float64(1)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := tx.Get("k1", &doc); err != nil {
+		t.Fatalf("Get within Tx: %v", err)
+	}
+	if doc["a"] != int64(1) {
+		t.Errorf("got doc %v, want a=1", doc)
+	}
+}
+
+func TestTxDoneReuseReturnsErrTxDone(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	tx := j.Begin()
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := tx.Put("k1", map[string]interface{}{"a": float64(1)}); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Put after Commit: got %v, want ErrTxDone", err)
+	}
+	if err := tx.Commit(); !errors.Is(err, ErrTxDone) {
+		t.Errorf("second Commit: got %v, want ErrTxDone", err)
+	}
+}
+
+func TestWithTxCommitsOnSuccess(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	err := j.WithTx(func(tx *Tx) error {
+		return tx.Put("k1", map[string]interface{}{"a": float64(1)})
+	})
+	if err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := j.Retrieve("k1", &doc); err != nil {
+		t.Fatalf("Retrieve after WithTx: %v", err)
+	}
+}
+
+func TestWithTxDiscardsOnError(t *testing.T) {
+	j := newTestDB(t, nil)
+	wantErr := errors.New("boom")
+
+	err := j.WithTx(func(tx *Tx) error {
+		if putErr := tx.Put("k1", map[string]interface{}{"a": float64(1)}); putErr != nil {
+			return putErr
+		}
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("WithTx: got %v, want %v", err, wantErr)
+	}
+
+	var doc map[string]interface{}
+	if err := j.Retrieve("k1", &doc); err == nil {
+		t.Errorf("Retrieve after failed WithTx: got %v, want not-found error", doc)
+	}
+
+	// The write lock must have been released, not wedged, by the discard.
+	if err := j.Store("k2", map[string]interface{}{"a": float64(2)}); err != nil {
+		t.Fatalf("Store after failed WithTx: %v", err)
+	}
+}
+
+func TestWithTxDiscardsAndRepanicsOnPanic(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("WithTx: expected panic to propagate")
+			}
+		}()
+		j.WithTx(func(tx *Tx) error {
+			tx.Put("k1", map[string]interface{}{"a": float64(1)})
+			panic("boom")
+		})
+	}()
+
+	// The write lock must have been released, not wedged, by the panic path.
+	if err := j.Store("k2", map[string]interface{}{"a": float64(2)}); err != nil {
+		t.Fatalf("Store after panicking WithTx: %v", err)
+	}
+}
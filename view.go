@@ -0,0 +1,472 @@
+package jotdb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.mills.io/bitcask/v2"
+)
+
+// ReduceFunc names a supported aggregation reducer for a ViewSpec's
+// GroupBy.
+type ReduceFunc string
+
+const (
+	ReduceCount ReduceFunc = "count"
+	ReduceSum   ReduceFunc = "sum"
+	ReduceMin   ReduceFunc = "min"
+	ReduceMax   ReduceFunc = "max"
+	ReduceAvg   ReduceFunc = "avg"
+)
+
+// viewKind distinguishes the on-disk key families a view writes under its
+// "view:<name>:" prefix.
+const (
+	viewKindRow     = "row"     // ungrouped view: one projected document per key
+	viewKindMembers = "members" // grouped view: key list belonging to one group
+	viewKindGroup   = "group"   // grouped view: the group's reduced aggregate
+)
+
+// ViewSpec describes a materialized view over every stored document. With
+// GroupBy unset, the view is a filtered, optionally projected copy of the
+// database, one row per document key. With GroupBy set, the view keeps one
+// row per distinct value of that dot-path field, aggregated by Reduce over
+// ReduceField (ReduceCount needs no ReduceField).
+type ViewSpec struct {
+	// Filter, if non-nil, excludes documents for which it returns false.
+	Filter func(doc map[string]interface{}) bool
+	// Project, if non-nil, transforms each document that passes Filter
+	// before it is stored in an ungrouped view. The default is the
+	// identity projection. Grouped views ignore Project: GroupBy and
+	// ReduceField are read from the unprojected document.
+	Project func(doc map[string]interface{}) map[string]interface{}
+	// GroupBy is the dot-path field documents are grouped by. Leave it
+	// unset for an ungrouped, per-document view.
+	GroupBy string
+	// Reduce names the aggregation applied within each GroupBy group.
+	Reduce ReduceFunc
+	// ReduceField is the dot-path field Reduce aggregates over.
+	ReduceField string
+}
+
+// viewRow is the on-disk representation of one materialized view row:
+// either a projected document (ungrouped views) or a group's running
+// aggregate (grouped views).
+type viewRow struct {
+	Doc   map[string]interface{} `json:"doc,omitempty"`
+	Count int64                  `json:"count,omitempty"`
+	Sum   float64                `json:"sum,omitempty"`
+	Min   float64                `json:"min,omitempty"`
+	Max   float64                `json:"max,omitempty"`
+	Avg   float64                `json:"avg,omitempty"`
+}
+
+// CreateView registers spec under name and performs an initial
+// RebuildView so the view reflects every document already stored. Views
+// are held in memory, like secondary indexes: re-register with the same
+// name and spec each time the database is reopened.
+func (j *JotDB) CreateView(name string, spec ViewSpec) error {
+	j.mu.Lock()
+	if j.views == nil {
+		j.views = make(map[string]ViewSpec)
+	}
+	j.views[name] = spec
+	j.mu.Unlock()
+
+	return j.RebuildView(name)
+}
+
+// RebuildView recomputes name's view from scratch by scanning every
+// stored document. Use it after CreateView on a database with existing
+// documents, or to repair a view that has drifted out of sync.
+func (j *JotDB) RebuildView(name string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	spec, ok := j.views[name]
+	if !ok {
+		return fmt.Errorf("view %q is not registered", name)
+	}
+
+	tx := j.db.Transaction()
+	defer tx.Discard()
+
+	prefix := []byte("view:" + name + ":")
+	var staleKeys [][]byte
+	err := tx.Scan(prefix, func(k bitcask.Key) error {
+		cp := make([]byte, len(k))
+		copy(cp, k)
+		staleKeys = append(staleKeys, cp)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range staleKeys {
+		if err := tx.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	err = tx.Scan([]byte("doc:"), func(k bitcask.Key) error {
+		data, err := tx.Get(k)
+		if err != nil {
+			return err
+		}
+		var docMap map[string]interface{}
+		if err := decodeDoc(data, &docMap); err != nil {
+			return err
+		}
+		return j.putView(tx, name, spec, string(k[len("doc:"):]), docMap)
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// QueryView reads one row from a registered view. For an ungrouped view
+// (ViewSpec.GroupBy unset), key is a stored document's key and the result
+// is that document's projection, if it passed the view's filter. For a
+// grouped view, key is a group-by value and the result is a single-row
+// summary of Reduce applied to that group.
+func (j *JotDB) QueryView(name string, key interface{}) ([]map[string]interface{}, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	spec, ok := j.views[name]
+	if !ok {
+		return nil, fmt.Errorf("view %q is not registered", name)
+	}
+
+	kind := viewKindRow
+	if spec.GroupBy != "" {
+		kind = viewKindGroup
+	}
+	rowKey, err := encodeViewKey(name, kind, key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := j.db.Get(rowKey)
+	if err != nil {
+		if err == bitcask.ErrKeyNotFound {
+			return []map[string]interface{}{}, nil
+		}
+		return nil, err
+	}
+
+	var row viewRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return nil, err
+	}
+
+	if spec.GroupBy == "" {
+		return []map[string]interface{}{row.Doc}, nil
+	}
+
+	result := map[string]interface{}{"count": row.Count}
+	switch spec.Reduce {
+	case ReduceSum:
+		result["sum"] = row.Sum
+	case ReduceMin:
+		result["min"] = row.Min
+	case ReduceMax:
+		result["max"] = row.Max
+	case ReduceAvg:
+		result["avg"] = row.Avg
+	}
+	return []map[string]interface{}{result}, nil
+}
+
+// putViews feeds key's document into every registered view, within the
+// given transaction.
+func (j *JotDB) putViews(tx *bitcask.Txn, key string, docMap map[string]interface{}) error {
+	for name, spec := range j.views {
+		if err := j.putView(tx, name, spec, key, docMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putView feeds key's document into the named view, within the given
+// transaction. It is a no-op if the document does not pass the view's
+// filter, or is missing its GroupBy field.
+func (j *JotDB) putView(tx *bitcask.Txn, name string, spec ViewSpec, key string, docMap map[string]interface{}) error {
+	if spec.Filter != nil && !spec.Filter(docMap) {
+		return nil
+	}
+
+	if spec.GroupBy == "" {
+		projected := docMap
+		if spec.Project != nil {
+			projected = spec.Project(docMap)
+		}
+		return j.putViewRow(tx, name, key, viewRow{Doc: projected})
+	}
+
+	groupVal, ok := evalPath(docMap, spec.GroupBy)
+	if !ok {
+		return nil
+	}
+	return j.addToGroup(tx, name, spec, groupVal, key)
+}
+
+// removeViews removes key's document from every registered view, within
+// the given transaction.
+func (j *JotDB) removeViews(tx *bitcask.Txn, key string, docMap map[string]interface{}) error {
+	for name, spec := range j.views {
+		if err := j.removeView(tx, name, spec, key, docMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeView removes key's document from the named view, within the
+// given transaction.
+func (j *JotDB) removeView(tx *bitcask.Txn, name string, spec ViewSpec, key string, docMap map[string]interface{}) error {
+	if spec.Filter != nil && !spec.Filter(docMap) {
+		return nil
+	}
+
+	if spec.GroupBy == "" {
+		rowKey, err := encodeViewKey(name, viewKindRow, key)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(rowKey); err != nil && err != bitcask.ErrKeyNotFound {
+			return err
+		}
+		return nil
+	}
+
+	groupVal, ok := evalPath(docMap, spec.GroupBy)
+	if !ok {
+		return nil
+	}
+	return j.removeFromGroup(tx, name, spec, groupVal, key)
+}
+
+// putViewRow stores row as the materialized row for an ungrouped view's
+// key, within the given transaction.
+func (j *JotDB) putViewRow(tx *bitcask.Txn, name, key string, row viewRow) error {
+	rowKey, err := encodeViewKey(name, viewKindRow, key)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return tx.Put(rowKey, data)
+}
+
+// addToGroup adds key to groupVal's member list for the named view and
+// recomputes the group's aggregate, within the given transaction.
+func (j *JotDB) addToGroup(tx *bitcask.Txn, name string, spec ViewSpec, groupVal interface{}, key string) error {
+	membersKey, err := encodeViewKey(name, viewKindMembers, groupVal)
+	if err != nil {
+		return err
+	}
+	members, err := readMemberList(tx, membersKey)
+	if err != nil {
+		return err
+	}
+	if !containsString(members, key) {
+		members = append(members, key)
+	}
+	if err := writeMemberList(tx, membersKey, members); err != nil {
+		return err
+	}
+	return j.recomputeGroup(tx, name, spec, groupVal, membersKey, members)
+}
+
+// removeFromGroup removes key from groupVal's member list for the named
+// view and recomputes (or, if the group is now empty, deletes) the
+// group's aggregate, within the given transaction.
+func (j *JotDB) removeFromGroup(tx *bitcask.Txn, name string, spec ViewSpec, groupVal interface{}, key string) error {
+	membersKey, err := encodeViewKey(name, viewKindMembers, groupVal)
+	if err != nil {
+		return err
+	}
+	members, err := readMemberList(tx, membersKey)
+	if err != nil {
+		return err
+	}
+	remaining := members[:0]
+	for _, m := range members {
+		if m != key {
+			remaining = append(remaining, m)
+		}
+	}
+
+	if len(remaining) == 0 {
+		rowKey, err := encodeViewKey(name, viewKindGroup, groupVal)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(membersKey); err != nil && err != bitcask.ErrKeyNotFound {
+			return err
+		}
+		if err := tx.Delete(rowKey); err != nil && err != bitcask.ErrKeyNotFound {
+			return err
+		}
+		return nil
+	}
+
+	if err := writeMemberList(tx, membersKey, remaining); err != nil {
+		return err
+	}
+	return j.recomputeGroup(tx, name, spec, groupVal, membersKey, remaining)
+}
+
+// recomputeGroup recomputes a group's aggregate row from scratch by
+// reading every one of its member documents, within the given
+// transaction. Recomputing from the member list, rather than adjusting a
+// running total, keeps Min/Max correct when a member is removed.
+func (j *JotDB) recomputeGroup(tx *bitcask.Txn, name string, spec ViewSpec, groupVal interface{}, membersKey []byte, members []string) error {
+	var row viewRow
+	haveExtremum := false
+	for _, m := range members {
+		data, err := tx.Get([]byte("doc:" + m))
+		if err != nil {
+			if err == bitcask.ErrKeyNotFound {
+				continue
+			}
+			return err
+		}
+		var doc map[string]interface{}
+		if err := decodeDoc(data, &doc); err != nil {
+			return err
+		}
+
+		row.Count++
+		if spec.Reduce == ReduceCount {
+			continue
+		}
+		raw, ok := evalPath(doc, spec.ReduceField)
+		if !ok {
+			continue
+		}
+		n, ok := numericValue(raw)
+		if !ok {
+			continue
+		}
+		row.Sum += n
+		if !haveExtremum {
+			row.Min, row.Max = n, n
+			haveExtremum = true
+		} else {
+			if n < row.Min {
+				row.Min = n
+			}
+			if n > row.Max {
+				row.Max = n
+			}
+		}
+	}
+	if row.Count > 0 {
+		row.Avg = row.Sum / float64(row.Count)
+	}
+
+	rowKey, err := encodeViewKey(name, viewKindGroup, groupVal)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	return tx.Put(rowKey, data)
+}
+
+// encodeViewKey builds the on-disk key for a view entry: the reserved
+// "view:<name>:<kind>:" prefix followed by a sortable encoding of value
+// (a group-by value for kind viewKindGroup/viewKindMembers, or a document
+// key for kind viewKindRow).
+func encodeViewKey(name, kind string, value interface{}) ([]byte, error) {
+	enc, err := encodeIndexValue(value)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 0, len("view:")+len(name)+len(kind)+2+len(enc))
+	key = append(key, "view:"...)
+	key = append(key, name...)
+	key = append(key, ':')
+	key = append(key, kind...)
+	key = append(key, ':')
+	key = append(key, enc...)
+	return key, nil
+}
+
+// readMemberList reads a view group's member key list, within the given
+// transaction, returning a nil slice if it does not exist yet.
+func readMemberList(tx *bitcask.Txn, membersKey []byte) ([]string, error) {
+	data, err := tx.Get(membersKey)
+	if err != nil {
+		if err == bitcask.ErrKeyNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var members []string
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// writeMemberList stores a view group's member key list, within the
+// given transaction.
+func writeMemberList(tx *bitcask.Txn, membersKey []byte, members []string) error {
+	data, err := json.Marshal(members)
+	if err != nil {
+		return err
+	}
+	return tx.Put(membersKey, data)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// numericValue converts a document field value to float64 for
+// aggregation, accepting any numeric type a document value might hold:
+// int64/float64 from the JSON/BSON parsers, or a native Go numeric type
+// from a struct stored via StoreStruct.
+func numericValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
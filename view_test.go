@@ -0,0 +1,113 @@
+package jotdb
+
+import "testing"
+
+func TestViewAggregatesGroupsIncrementally(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	spec := ViewSpec{
+		GroupBy:     "category",
+		Reduce:      ReduceSum,
+		ReduceField: "price",
+	}
+	if err := j.CreateView("by_category", spec); err != nil {
+		t.Fatalf("CreateView: %v", err)
+	}
+
+	if err := j.Store("p1", map[string]interface{}{"category": "fruit", "price": float64(10)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Store("p2", map[string]interface{}{"category": "fruit", "price": float64(5)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	rows, err := j.QueryView("by_category", "fruit")
+	if err != nil {
+		t.Fatalf("QueryView: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("QueryView(\"by_category\", \"fruit\") = %v, want 1 row", rows)
+	}
+	if rows[0]["count"] != int64(2) || rows[0]["sum"] != float64(15) {
+		t.Errorf("QueryView row = %v, want count=2 sum=15", rows[0])
+	}
+
+	// Deleting a member recomputes the aggregate, not just decrements it.
+	if err := j.Delete("p2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	rows, err = j.QueryView("by_category", "fruit")
+	if err != nil {
+		t.Fatalf("QueryView after Delete: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("QueryView(\"by_category\", \"fruit\") after Delete = %v, want 1 row", rows)
+	}
+	if rows[0]["count"] != int64(1) || rows[0]["sum"] != float64(10) {
+		t.Errorf("QueryView row after Delete = %v, want count=1 sum=10", rows[0])
+	}
+}
+
+func TestStoreMovesDocumentBetweenGroupsOnUpdate(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	spec := ViewSpec{GroupBy: "group", Reduce: ReduceCount}
+	if err := j.CreateView("by_group", spec); err != nil {
+		t.Fatalf("CreateView: %v", err)
+	}
+
+	if err := j.Store("k1", map[string]interface{}{"group": "X"}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := j.Store("k1", map[string]interface{}{"group": "Y"}); err != nil {
+		t.Fatalf("re-Store: %v", err)
+	}
+
+	rowsX, err := j.QueryView("by_group", "X")
+	if err != nil {
+		t.Fatalf("QueryView(X): %v", err)
+	}
+	if len(rowsX) != 0 {
+		t.Errorf("QueryView(\"by_group\", \"X\") after moving to Y = %v, want no row", rowsX)
+	}
+
+	rowsY, err := j.QueryView("by_group", "Y")
+	if err != nil {
+		t.Fatalf("QueryView(Y): %v", err)
+	}
+	if len(rowsY) != 1 || rowsY[0]["count"] != int64(1) {
+		t.Errorf("QueryView(\"by_group\", \"Y\") = %v, want count=1", rowsY)
+	}
+}
+
+func TestRebuildViewFromScratch(t *testing.T) {
+	j := newTestDB(t, nil)
+
+	if err := j.Store("p1", map[string]interface{}{"category": "fruit", "price": float64(10)}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	spec := ViewSpec{GroupBy: "category", Reduce: ReduceCount}
+	if err := j.CreateView("by_category", spec); err != nil {
+		t.Fatalf("CreateView: %v", err)
+	}
+
+	rows, err := j.QueryView("by_category", "fruit")
+	if err != nil {
+		t.Fatalf("QueryView: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["count"] != int64(1) {
+		t.Fatalf("QueryView after CreateView = %v, want count=1", rows)
+	}
+
+	if err := j.RebuildView("by_category"); err != nil {
+		t.Fatalf("RebuildView: %v", err)
+	}
+	rows, err = j.QueryView("by_category", "fruit")
+	if err != nil {
+		t.Fatalf("QueryView after RebuildView: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["count"] != int64(1) {
+		t.Fatalf("QueryView after RebuildView = %v, want count=1", rows)
+	}
+}